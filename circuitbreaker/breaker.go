@@ -0,0 +1,504 @@
+// Package circuitbreaker implements a simple circuit breaker: CLOSED lets
+// calls through, OPEN short-circuits them, HALFOPEN probes the service
+// again before fully recovering.
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status int
+
+const (
+	CLOSED   Status = 1
+	OPEN     Status = 2
+	HALFOPEN Status = 3
+)
+
+// ErrTooManyRequests is returned when a HALFOPEN breaker already has
+// MaxHalfOpenRequests probes in flight and can't admit another one.
+var ErrTooManyRequests = errors.New("CB: too many requests in HALFOPEN state")
+
+// Defaults applied by NewCircuitBreaker when Settings leaves BucketCount or
+// BucketDuration unset, so an unconfigured window can't divide by zero.
+const (
+	defaultBucketCount    = 10
+	defaultBucketDuration = time.Second
+)
+
+// bucket accumulates call outcomes for one slice of wall-clock time.
+// windowStart identifies which slice the counts belong to, so a bucket that
+// wasn't touched for a full revolution of the ring can be recognized as
+// stale and lazily zeroed on its next write.
+type bucket struct {
+	windowStart time.Time
+	requests    int
+	successes   int
+	failures    int
+}
+
+// Counts is a snapshot of request outcomes observed in the current window,
+// handed to ReadyToTrip so callers can implement their own trip policy.
+// ConsecutiveSuccesses/ConsecutiveFailures are the current running streaks
+// and are not limited to the window.
+type Counts struct {
+	Requests             int
+	TotalSuccesses       int
+	TotalFailures        int
+	ConsecutiveSuccesses int
+	ConsecutiveFailures  int
+}
+
+// Settings configures a CircuitBreaker. Zero-value ReadyToTrip/IsSuccessful/
+// OnStateChange fall back to sensible defaults (see NewCircuitBreaker).
+type Settings struct {
+	// Name identifies this breaker in OnStateChange callbacks.
+	Name string
+	// BucketCount/BucketDuration size the rolling time window used to compute
+	// Counts. Zero values default to defaultBucketCount/defaultBucketDuration.
+	BucketCount    int
+	BucketDuration time.Duration
+	// Timeout - сколько времени у CB восстановиться после OPEN
+	Timeout time.Duration
+	// RecoveryRequests - CB переходит из HALFOPEN в CLOSED, когда успешных запросов в HALFOPEN стало больше RecoveryRequests
+	RecoveryRequests int
+	// MaxHalfOpenRequests - сколько пробных запросов одновременно допускается
+	// в HALFOPEN. 0 означает "без ограничения". Лишние вызовы получают ErrTooManyRequests.
+	MaxHalfOpenRequests int
+	// PushTimeout bounds how long CallContext waits for service before
+	// counting the call as a failure and returning early (0 = no bound).
+	// Named after Mimir's ingester.circuit-breaker.push-timeout.
+	PushTimeout time.Duration
+	// ReadyToTrip решает, пора ли переходить в OPEN, на основе Counts за окно.
+	// По умолчанию используется Percentile/MinRequests.
+	ReadyToTrip func(Counts) bool
+	// Percentile и MinRequests используются дефолтной ReadyToTrip.
+	Percentile  float64
+	MinRequests int
+	// IsSuccessful классифицирует ошибку сервиса: true - не считать отказом.
+	// По умолчанию любая ошибка - отказ.
+	IsSuccessful func(err error) bool
+	// OnStateChange вызывается при каждом переходе состояния вместо логов.
+	OnStateChange func(name string, from, to Status)
+}
+
+type CircuitBreaker struct {
+	mu sync.Mutex
+	// Name идентифицирует CB в OnStateChange
+	name string
+	// CLOSED - work!, OPEN - fail!, HALFOPEN - work until fail!
+	state Status
+	// Сколько времени у CB восстановиться
+	timeout time.Duration
+
+	lastAttemptedAt time.Time
+	// Процент запросов после которого открывается CB (используется дефолтной ReadyToTrip)
+	percentile float64
+	// Buckets хранят количество запросов/успехов/ошибок за скользящее окно
+	// времени: интервал [t, t+bucketDuration) попадает в buckets[(t/bucketDuration) % len(buckets)]
+	buckets []bucket
+	// Длительность одного bucket'а
+	bucketDuration time.Duration
+	// Минимальное число запросов в окне, ниже которого CB не открывается,
+	// даже если percentile превышен (используется дефолтной ReadyToTrip)
+	minRequests int
+	// В CLOSED переходим, когда successCount превысит это значение
+	recoveryRequests int
+	// Сколько успешных запросов в HALFOPEN уже сделано
+	successCount int
+	// Текущая длина серии подряд идущих успехов/отказов
+	consecutiveSuccesses int
+	consecutiveFailures  int
+	// Ограничение на число одновременных пробных запросов в HALFOPEN (0 - без ограничения)
+	maxHalfOpenRequests int
+	// Сколько пробных запросов в HALFOPEN сейчас в полёте
+	inFlightHalfOpen int
+	// halfOpenGen bumps every time the breaker enters HALFOPEN, so a slot
+	// released by a probe from a previous HALFOPEN episode (still in flight
+	// when the breaker cycled OPEN->HALFOPEN again) is recognized as stale
+	// and ignored instead of decrementing the fresh generation's counter.
+	halfOpenGen int
+	// Сколько ждать service в CallContext, прежде чем засчитать отказ (0 - не ограничено)
+	pushTimeout time.Duration
+
+	readyToTrip   func(Counts) bool
+	isSuccessful  func(err error) bool
+	onStateChange func(name string, from, to Status)
+
+	// metrics is set by Metrics; nil means metrics are disabled.
+	metrics atomic.Pointer[metrics]
+}
+
+func NewCircuitBreaker(st Settings) *CircuitBreaker {
+	bucketCount := st.BucketCount
+	if bucketCount <= 0 {
+		bucketCount = defaultBucketCount
+	}
+	bucketDuration := st.BucketDuration
+	if bucketDuration <= 0 {
+		bucketDuration = defaultBucketDuration
+	}
+
+	c := &CircuitBreaker{
+		name:                st.Name,
+		state:               CLOSED,
+		timeout:             st.Timeout,
+		percentile:          st.Percentile,
+		buckets:             make([]bucket, bucketCount),
+		bucketDuration:      bucketDuration,
+		minRequests:         st.MinRequests,
+		recoveryRequests:    st.RecoveryRequests,
+		maxHalfOpenRequests: st.MaxHalfOpenRequests,
+		pushTimeout:         st.PushTimeout,
+		readyToTrip:         st.ReadyToTrip,
+		isSuccessful:        st.IsSuccessful,
+		onStateChange:       st.OnStateChange,
+	}
+
+	if c.readyToTrip == nil {
+		c.readyToTrip = c.defaultReadyToTrip
+	}
+	if c.isSuccessful == nil {
+		c.isSuccessful = func(err error) bool { return err == nil }
+	}
+
+	return c
+}
+
+// defaultReadyToTrip trips once the window has seen at least minRequests
+// calls and the failure ratio reaches percentile.
+func (c *CircuitBreaker) defaultReadyToTrip(counts Counts) bool {
+	if counts.Requests < c.minRequests {
+		return false
+	}
+	return float64(counts.TotalFailures)/float64(counts.Requests) >= c.percentile
+}
+
+// admit decides whether a call may proceed: it rejects outright while OPEN,
+// and bounds concurrent probes while HALFOPEN. On success it returns a
+// release func that must be deferred by the caller (a no-op outside
+// HALFOPEN).
+func (c *CircuitBreaker) admit() (release func(), err error) {
+	c.setActualState()
+
+	state := c.getState()
+	if state == OPEN {
+		c.recordResult(resultOpen, 0)
+		return nil, errors.New("CB IS OPEN")
+	}
+
+	if state == HALFOPEN {
+		release, ok := c.tryAcquireHalfOpenSlot()
+		if !ok {
+			c.recordResult(resultOpen, 0)
+			return nil, ErrTooManyRequests
+		}
+		return release, nil
+	}
+
+	return func() {}, nil
+}
+
+// account classifies err via IsSuccessful, updates the breaker's counters
+// and metrics accordingly, and returns err unchanged.
+func (c *CircuitBreaker) account(err error, elapsed time.Duration) error {
+	if err != nil && !c.isSuccessful(err) {
+		c.onError()
+		c.recordResult(resultError, elapsed)
+		return err
+	}
+
+	c.onSuccess()
+	c.recordResult(resultSuccess, elapsed)
+
+	return err
+}
+
+// Execute runs req through the circuit breaker and returns its typed result.
+// Unlike Call, the caller doesn't need to smuggle a result out through a
+// closure-captured variable.
+func Execute[T any](c *CircuitBreaker, req func() (T, error)) (T, error) {
+	var zero T
+
+	release, err := c.admit()
+	if err != nil {
+		return zero, err
+	}
+	defer release()
+
+	start := time.Now()
+	result, err := req()
+	elapsed := time.Since(start)
+
+	if err != nil && !c.isSuccessful(err) {
+		return zero, c.account(err, elapsed)
+	}
+
+	return result, c.account(err, elapsed)
+}
+
+// CallContext runs service through the circuit breaker. Cancelling ctx
+// makes CallContext stop waiting on service, but service keeps running in
+// the background until it returns; its result is then discarded and
+// uncounted. If PushTimeout is set and service doesn't return within it,
+// the call is counted as a failure and CallContext returns a wrapped
+// timeout error right away, even though service may still be running.
+func (c *CircuitBreaker) CallContext(ctx context.Context, service func(context.Context) error) error {
+	release, err := c.admit()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- service(ctx)
+	}()
+
+	var timeout <-chan time.Time
+	if c.pushTimeout > 0 {
+		timer := time.NewTimer(c.pushTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-done:
+		return c.account(err, time.Since(start))
+
+	case <-timeout:
+		c.onError()
+		c.recordResult(resultError, time.Since(start))
+		go func() { <-done }() // drain once service eventually returns, so its goroutine doesn't leak
+		return fmt.Errorf("circuitbreaker: service exceeded push timeout of %s: %w", c.pushTimeout, context.DeadlineExceeded)
+
+	case <-ctx.Done():
+		go func() { <-done }()
+		return ctx.Err()
+	}
+}
+
+// Call is a non-generic shim over CallContext for services that don't need
+// cancellation or a per-call timeout.
+func (c *CircuitBreaker) Call(service func() error) error {
+	return c.CallContext(context.Background(), func(context.Context) error {
+		return service()
+	})
+}
+
+func (c *CircuitBreaker) getState() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *CircuitBreaker) setActualState() {
+	c.mu.Lock()
+
+	var changed bool
+	var from, to Status
+
+	switch c.state {
+	case OPEN:
+		if elapsed := time.Since(c.lastAttemptedAt); elapsed > c.timeout {
+			from, to = c.transitionTo(HALFOPEN)
+			changed = true
+		}
+
+	case HALFOPEN:
+		if c.successCount > c.recoveryRequests {
+			from, to = c.transitionTo(CLOSED)
+			changed = true
+
+			c.buckets = make([]bucket, len(c.buckets)) // сбрасываем окно
+			c.successCount = 0                         // сбрасываем счетчик успешных запросов
+		}
+	case CLOSED:
+		if c.readyToTrip(c.countsLocked(time.Now())) {
+			from, to = c.transitionTo(OPEN)
+			changed = true
+			c.lastAttemptedAt = time.Now()
+		}
+	}
+
+	c.mu.Unlock()
+
+	if changed {
+		c.notifyStateChange(from, to)
+	}
+}
+
+// transitionTo changes state and returns the transition so the caller can
+// notify OnStateChange once c.mu is released. Must be called with c.mu held.
+func (c *CircuitBreaker) transitionTo(to Status) (from, newState Status) {
+	from = c.state
+	c.state = to
+
+	if to == HALFOPEN {
+		c.inFlightHalfOpen = 0
+		c.halfOpenGen++
+	}
+
+	c.reportStateGauge(to)
+
+	return from, to
+}
+
+// notifyStateChange invokes OnStateChange if set and the state actually
+// changed. Must be called without c.mu held, so the callback can safely
+// call back into the breaker (State, Counts, Call, ...) without deadlocking.
+func (c *CircuitBreaker) notifyStateChange(from, to Status) {
+	if c.onStateChange != nil && from != to {
+		c.onStateChange(c.name, from, to)
+	}
+}
+
+// tryAcquireHalfOpenSlot admits one more probe request while in HALFOPEN,
+// bounded by maxHalfOpenRequests (0 = unlimited). The returned release func
+// is tied to the HALFOPEN generation active at acquire time, so it's a
+// no-op if the breaker has since cycled through another HALFOPEN episode.
+func (c *CircuitBreaker) tryAcquireHalfOpenSlot() (release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxHalfOpenRequests > 0 && c.inFlightHalfOpen >= c.maxHalfOpenRequests {
+		return nil, false
+	}
+
+	c.inFlightHalfOpen++
+	gen := c.halfOpenGen
+	return func() { c.releaseHalfOpenSlot(gen) }, true
+}
+
+func (c *CircuitBreaker) releaseHalfOpenSlot(gen int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.halfOpenGen {
+		return
+	}
+	c.inFlightHalfOpen--
+}
+
+// countsLocked builds a Counts snapshot from the rolling window. Must be
+// called with c.mu held.
+func (c *CircuitBreaker) countsLocked(now time.Time) Counts {
+	requests, failures := c.windowCounts(now)
+
+	return Counts{
+		Requests:             requests,
+		TotalSuccesses:       requests - failures,
+		TotalFailures:        failures,
+		ConsecutiveSuccesses: c.consecutiveSuccesses,
+		ConsecutiveFailures:  c.consecutiveFailures,
+	}
+}
+
+// bucketFor returns the bucket responsible for t, zeroing it first if it
+// belongs to a window that has since expired (lazy expiry).
+func (c *CircuitBreaker) bucketFor(t time.Time) *bucket {
+	windowStart := t.Truncate(c.bucketDuration)
+	idx := int((windowStart.UnixNano() / int64(c.bucketDuration)) % int64(len(c.buckets)))
+	if idx < 0 {
+		idx += len(c.buckets)
+	}
+
+	b := &c.buckets[idx]
+	if !b.windowStart.Equal(windowStart) {
+		*b = bucket{windowStart: windowStart}
+	}
+
+	return b
+}
+
+// windowCounts aggregates requests/failures across buckets that still fall
+// within the last len(buckets)*bucketDuration of wall time. Buckets that
+// haven't been written to recently are treated as expired even though they
+// haven't been zeroed yet.
+func (c *CircuitBreaker) windowCounts(now time.Time) (requests, failures int) {
+	span := time.Duration(len(c.buckets)) * c.bucketDuration
+
+	for i := range c.buckets {
+		b := &c.buckets[i]
+		if b.windowStart.IsZero() || now.Sub(b.windowStart) > span {
+			continue
+		}
+
+		requests += b.requests
+		failures += b.failures
+	}
+
+	return requests, failures
+}
+
+func (c *CircuitBreaker) onSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.bucketFor(time.Now())
+	b.requests++
+	b.successes++
+
+	c.consecutiveSuccesses++
+	c.consecutiveFailures = 0
+
+	if c.state == HALFOPEN {
+		c.successCount++
+	}
+
+	c.reportWindowGauges()
+}
+
+func (c *CircuitBreaker) onError() {
+	c.mu.Lock()
+
+	b := c.bucketFor(time.Now())
+	b.requests++
+	b.failures++
+
+	c.consecutiveFailures++
+	c.consecutiveSuccesses = 0
+
+	var changed bool
+	var from, to Status
+
+	if c.state == HALFOPEN {
+		from, to = c.transitionTo(OPEN)
+		changed = true
+		c.successCount = 0
+	}
+
+	c.lastAttemptedAt = time.Now()
+	c.successCount = 0
+
+	c.reportWindowGauges()
+
+	c.mu.Unlock()
+
+	if changed {
+		c.notifyStateChange(from, to)
+	}
+}
+
+// State returns the breaker's current state.
+func (c *CircuitBreaker) State() Status {
+	return c.getState()
+}
+
+// Counts returns a snapshot of request counts in the current window.
+func (c *CircuitBreaker) Counts() Counts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.countsLocked(time.Now())
+}
+
+// Name returns the breaker's configured name.
+func (c *CircuitBreaker) Name() string {
+	return c.name
+}
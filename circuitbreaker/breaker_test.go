@@ -0,0 +1,293 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+)
+
+var errBoom = errors.New("boom")
+
+func newTestBreaker(t *testing.T, st circuitbreaker.Settings) *circuitbreaker.CircuitBreaker {
+	t.Helper()
+
+	if st.BucketCount == 0 {
+		st.BucketCount = 5
+	}
+	if st.BucketDuration == 0 {
+		st.BucketDuration = time.Hour // effectively one big window for most tests
+	}
+
+	return circuitbreaker.NewCircuitBreaker(st)
+}
+
+func TestRollingWindowTripsAndRecovers(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:          20 * time.Millisecond,
+		Percentile:       0.5,
+		MinRequests:      4,
+		RecoveryRequests: 2,
+	})
+
+	// setActualState evaluates ReadyToTrip against counts from *before* the
+	// current call, so the breaker only trips on the call after the one
+	// that reaches MinRequests/Percentile.
+	for i := 0; i < 4; i++ {
+		if err := cb.Call(func() error { return errBoom }); !errors.Is(err, errBoom) {
+			t.Fatalf("call %d: got %v, want errBoom", i, err)
+		}
+	}
+
+	if err := cb.Call(func() error { return nil }); err == nil {
+		t.Fatal("expected a rejection once the breaker trips, got nil")
+	}
+
+	if state := cb.State(); state != circuitbreaker.OPEN {
+		t.Fatalf("state = %v, want OPEN after exceeding the failure threshold", state)
+	}
+
+	time.Sleep(30 * time.Millisecond) // let Timeout elapse
+
+	for i := 0; i < 3; i++ {
+		if err := cb.Call(func() error { return nil }); err != nil {
+			t.Fatalf("recovery call %d: got %v, want nil", i, err)
+		}
+	}
+
+	// Same lag as above: the HALFOPEN->CLOSED transition is only detected on
+	// the precheck of the call after successCount exceeds RecoveryRequests.
+	if err := cb.Call(func() error { return nil }); err != nil {
+		t.Fatalf("post-recovery call: got %v, want nil", err)
+	}
+
+	if state := cb.State(); state != circuitbreaker.CLOSED {
+		t.Fatalf("state = %v, want CLOSED after RecoveryRequests successes", state)
+	}
+}
+
+func TestExecuteReturnsResultOnClassifiedSuccessError(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:      time.Second,
+		Percentile:   0.5,
+		MinRequests:  1,
+		IsSuccessful: func(err error) bool { return errors.Is(err, errBoom) },
+	})
+
+	result, err := circuitbreaker.Execute(cb, func() (int, error) {
+		return 42, errBoom
+	})
+
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("err = %v, want errBoom", err)
+	}
+	if result != 42 {
+		t.Fatalf("result = %d, want 42 (must not be discarded on a classified-success error)", result)
+	}
+}
+
+func TestZeroValueSettingsDoesNotPanic(t *testing.T) {
+	cb := circuitbreaker.NewCircuitBreaker(circuitbreaker.Settings{})
+
+	if err := cb.Call(func() error { return nil }); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+}
+
+func TestMaxHalfOpenRequestsCapsConcurrentProbes(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:             10 * time.Millisecond,
+		Percentile:          0.5,
+		MinRequests:         1,
+		MaxHalfOpenRequests: 1,
+	})
+
+	if err := cb.Call(func() error { return errBoom }); !errors.Is(err, errBoom) {
+		t.Fatalf("tripping call: got %v, want errBoom", err)
+	}
+	// The breaker only evaluates ReadyToTrip on the *next* call, so this one
+	// observes and trips on the prior failure.
+	if err := cb.Call(func() error { return nil }); err == nil {
+		t.Fatal("expected a rejection once the breaker trips, got nil")
+	}
+	if state := cb.State(); state != circuitbreaker.OPEN {
+		t.Fatalf("state = %v, want OPEN", state)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let Timeout elapse so the next Call sees HALFOPEN
+
+	release := make(chan struct{})
+	probeStarted := make(chan struct{})
+	var probeErr error
+	done := make(chan struct{})
+	go func() {
+		probeErr = cb.Call(func() error {
+			close(probeStarted)
+			<-release
+			return nil
+		})
+		close(done)
+	}()
+
+	<-probeStarted
+
+	if err := cb.Call(func() error { return nil }); !errors.Is(err, circuitbreaker.ErrTooManyRequests) {
+		t.Fatalf("second concurrent probe: got %v, want ErrTooManyRequests", err)
+	}
+
+	close(release)
+	<-done
+
+	if probeErr != nil {
+		t.Fatalf("first probe: got %v, want nil", probeErr)
+	}
+}
+
+// TestHalfOpenSlotReleaseIgnoresStaleGeneration exercises the scenario from
+// the chunk0-4 review: a HALFOPEN probe (B) is still in flight when another
+// probe (A) fails and flips the breaker back to OPEN, Timeout then elapses
+// and the breaker cycles into a fresh HALFOPEN episode before B finally
+// returns and releases its slot. B's late release must not free a slot in
+// the new episode.
+func TestHalfOpenSlotReleaseIgnoresStaleGeneration(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:             15 * time.Millisecond,
+		Percentile:          0.5,
+		MinRequests:         1,
+		MaxHalfOpenRequests: 2,
+		RecoveryRequests:    5, // high enough that B's lone success can't trip recovery early
+	})
+
+	// Trip to OPEN: the breaker only detects it on the precheck of the call
+	// after the one that reaches MinRequests/Percentile.
+	if err := cb.Call(func() error { return errBoom }); !errors.Is(err, errBoom) {
+		t.Fatalf("tripping call: got %v, want errBoom", err)
+	}
+	if err := cb.Call(func() error { return nil }); err == nil {
+		t.Fatal("expected a rejection once the breaker trips, got nil")
+	}
+
+	time.Sleep(20 * time.Millisecond) // let Timeout elapse so the next Call sees HALFOPEN (gen 1)
+
+	bStarted := make(chan struct{})
+	bRelease := make(chan struct{})
+	bDone := make(chan struct{})
+	go func() {
+		cb.Call(func() error {
+			close(bStarted)
+			<-bRelease
+			return nil
+		})
+		close(bDone)
+	}()
+	<-bStarted
+
+	// A fails while B is still in flight, flipping HALFOPEN -> OPEN (gen 1).
+	if err := cb.Call(func() error { return errBoom }); !errors.Is(err, errBoom) {
+		t.Fatalf("probe A: got %v, want errBoom", err)
+	}
+	if state := cb.State(); state != circuitbreaker.OPEN {
+		t.Fatalf("state = %v, want OPEN after probe A failed", state)
+	}
+
+	time.Sleep(20 * time.Millisecond) // let Timeout elapse again so the next Call enters HALFOPEN gen 2
+
+	cStarted := make(chan struct{})
+	cRelease := make(chan struct{})
+	cDone := make(chan struct{})
+	go func() {
+		cb.Call(func() error {
+			close(cStarted)
+			<-cRelease
+			return nil
+		})
+		close(cDone)
+	}()
+	<-cStarted
+
+	dStarted := make(chan struct{})
+	dRelease := make(chan struct{})
+	dDone := make(chan struct{})
+	go func() {
+		cb.Call(func() error {
+			close(dStarted)
+			<-dRelease
+			return nil
+		})
+		close(dDone)
+	}()
+	<-dStarted
+
+	// Gen 2 is now at its cap (C + D). B (gen 1) finally returns and releases
+	// its stale slot; that release must be ignored.
+	close(bRelease)
+	<-bDone
+
+	if err := cb.Call(func() error { return nil }); !errors.Is(err, circuitbreaker.ErrTooManyRequests) {
+		t.Fatalf("probe E: got %v, want ErrTooManyRequests (B's stale release must not free a gen-2 slot)", err)
+	}
+
+	close(cRelease)
+	close(dRelease)
+	<-cDone
+	<-dDone
+}
+
+func TestCallContextPushTimeoutCountsAsFailure(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:     time.Second,
+		Percentile:  0.5,
+		MinRequests: 1,
+		PushTimeout: 20 * time.Millisecond,
+	})
+
+	var serviceReturned atomic.Bool
+	err := cb.CallContext(context.Background(), func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		serviceReturned.Store(true)
+		return nil
+	})
+
+	if err == nil {
+		t.Fatal("expected a push-timeout error, got nil")
+	}
+
+	counts := cb.Counts()
+	if counts.TotalFailures != 1 {
+		t.Fatalf("counts = %+v, want exactly 1 failure", counts)
+	}
+
+	time.Sleep(150 * time.Millisecond) // let the abandoned service goroutine finish
+	if !serviceReturned.Load() {
+		t.Fatal("service never finished; the draining goroutine may have leaked")
+	}
+}
+
+func TestCallContextCancelDoesNotBlockOnSlowService(t *testing.T) {
+	cb := newTestBreaker(t, circuitbreaker.Settings{
+		Timeout:     time.Second,
+		Percentile:  0.5,
+		MinRequests: 1,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	err := cb.CallContext(ctx, func(ctx context.Context) error {
+		defer wg.Done()
+		time.Sleep(50 * time.Millisecond)
+		return errBoom
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	wg.Wait() // the service's own goroutine should still complete, just uncounted
+}
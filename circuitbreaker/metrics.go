@@ -0,0 +1,124 @@
+package circuitbreaker
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// result labels used by the cb_results_total counter, named after Mimir's
+// ingester circuit breaker (resultSuccess, resultError, resultOpen).
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+	resultOpen    = "open"
+)
+
+// metrics holds the Prometheus instruments registered for one CircuitBreaker.
+type metrics struct {
+	state           prometheus.Gauge
+	results         *prometheus.CounterVec
+	duration        prometheus.Histogram
+	windowSuccesses prometheus.Gauge
+	windowFailures  prometheus.Gauge
+}
+
+// Metrics registers Prometheus instruments describing this breaker's state
+// and call outcomes with registerer, and returns c so it can be chained
+// after NewCircuitBreaker. Every call builds and registers a fresh set of
+// instruments, so calling it twice with the same registerer panics in
+// MustRegister on the duplicate; after calls with different registerers
+// only the instruments from the most recent call are updated.
+func (c *CircuitBreaker) Metrics(registerer prometheus.Registerer) *CircuitBreaker {
+	labels := prometheus.Labels{"name": c.name}
+
+	m := &metrics{
+		state: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "cb_state",
+			Help:        "Current state of the circuit breaker (0=CLOSED, 1=OPEN, 2=HALFOPEN).",
+			ConstLabels: labels,
+		}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "cb_results_total",
+			Help:        "Count of circuit breaker call results.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "cb_call_duration_seconds",
+			Help:        "Duration of calls executed through the circuit breaker.",
+			ConstLabels: labels,
+		}),
+		windowSuccesses: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "cb_window_successes",
+			Help:        "Successful calls in the current rolling window.",
+			ConstLabels: labels,
+		}),
+		windowFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "cb_window_failures",
+			Help:        "Failed calls in the current rolling window.",
+			ConstLabels: labels,
+		}),
+	}
+
+	registerer.MustRegister(m.state, m.results, m.duration, m.windowSuccesses, m.windowFailures)
+
+	m.state.Set(stateMetricValue(c.State()))
+	counts := c.Counts()
+	m.windowSuccesses.Set(float64(counts.TotalSuccesses))
+	m.windowFailures.Set(float64(counts.TotalFailures))
+
+	c.metrics.Store(m)
+
+	return c
+}
+
+// stateMetricValue maps Status to the 0/1/2 values exposed by cb_state,
+// independent of the internal Status values (1/2/3).
+func stateMetricValue(s Status) float64 {
+	switch s {
+	case CLOSED:
+		return 0
+	case OPEN:
+		return 1
+	case HALFOPEN:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// recordResult is a no-op when metrics are disabled.
+func (c *CircuitBreaker) recordResult(result string, duration time.Duration) {
+	m := c.metrics.Load()
+	if m == nil {
+		return
+	}
+
+	m.results.WithLabelValues(result).Inc()
+	if result != resultOpen {
+		m.duration.Observe(duration.Seconds())
+	}
+}
+
+// reportStateGauge is a no-op when metrics are disabled. Must be called
+// with c.mu held.
+func (c *CircuitBreaker) reportStateGauge(to Status) {
+	m := c.metrics.Load()
+	if m == nil {
+		return
+	}
+	m.state.Set(stateMetricValue(to))
+}
+
+// reportWindowGauges is a no-op when metrics are disabled. Must be called
+// with c.mu held.
+func (c *CircuitBreaker) reportWindowGauges() {
+	m := c.metrics.Load()
+	if m == nil {
+		return
+	}
+
+	counts := c.countsLocked(time.Now())
+	m.windowSuccesses.Set(float64(counts.TotalSuccesses))
+	m.windowFailures.Set(float64(counts.TotalFailures))
+}
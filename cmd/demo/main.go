@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+)
+
+func main() {
+	// Инициализация circuit breaker
+	breaker := circuitbreaker.NewCircuitBreaker(circuitbreaker.Settings{
+		Name:                "demo",
+		BucketCount:         10,
+		BucketDuration:      500 * time.Millisecond,
+		Timeout:             2 * time.Second,
+		Percentile:          0.30,
+		MinRequests:         20,
+		RecoveryRequests:    10,
+		MaxHalfOpenRequests: 5,
+		OnStateChange: func(name string, from, to circuitbreaker.Status) {
+			fmt.Printf("\n[%s] Switching from %d to %d state\n", name, from, to)
+		},
+	})
+
+	var err error
+	successfulService := func() error {
+		return nil
+	}
+
+	failingService := func() error {
+		return errors.New("service error")
+	}
+
+	// Исполняем успешные запросы
+	fmt.Println("Sending successful requests...")
+	for i := 0; i < 80; i++ {
+		if err = breaker.Call(successfulService); err != nil {
+			fmt.Printf("Service call failed: %s\n", err.Error())
+		}
+		fmt.Println(i, " ok ")
+
+	}
+
+	// Исполняем запросы с ошибкой
+	fmt.Println("\nSending failing requests...")
+
+	for i := 0; i < 40; i++ {
+		if err = breaker.Call(failingService); err != nil {
+			fmt.Printf("%d Service call failed: %s\n", i, err.Error())
+		}
+	}
+
+	// Ожидаем, чтобы CircuitBreaker перешел в half-open state
+	fmt.Printf("\nWaiting for circuit breaker to switch to half-open state...\n")
+	time.Sleep(3 * time.Second)
+
+	// Исполняем запросы для перехода в closed state
+	fmt.Println("Sending successful requests to recover...")
+	for i := 0; i < 15; i++ {
+		if err = breaker.Call(successfulService); err != nil {
+			fmt.Printf("Service call failed: %s\n", err.Error())
+		}
+
+		fmt.Printf("%d ok\n", i)
+	}
+
+	// Исполняем запросы с ошибкой для перехода обратно в open state
+	fmt.Printf("\nSending failing requests to switch back to open state 1 ...\n\n")
+	for i := 0; i < 40; i++ {
+		if err = breaker.Call(failingService); err != nil {
+			fmt.Printf("%d Service call failed: %s\n", i, err.Error())
+		}
+	}
+
+	// Ожидаем, чтобы CircuitBreaker перешел в half-open state
+	fmt.Printf("\nWaiting for circuit breaker to switch to half-open state...\n")
+	time.Sleep(3 * time.Second)
+
+	// Исполняем запросы с ошибкой для перехода обратно в open state
+	fmt.Printf("\nSending failing requests to switch back to open state 2 ...\n")
+	for i := 0; i < 10; i++ {
+		if err = breaker.Call(failingService); err != nil {
+			fmt.Printf("%d Service call failed: %s\n", i, err.Error())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Package grpcbreaker adapts circuitbreaker.CircuitBreaker to gRPC client
+// interceptors. Unlike cb.Settings.IsSuccessful, which the constructor can't
+// enforce, the interceptors classify outcomes themselves via Options.IsSuccessful
+// (default DefaultIsSuccessful), so a caller who forgets to configure
+// anything still gets Unavailable/DeadlineExceeded treated as failures.
+package grpcbreaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+)
+
+// IsSuccessful builds a circuitbreaker.Settings.IsSuccessful classifier that
+// treats the given gRPC status codes as breaker failures; every other
+// status, including a nil error, counts as success.
+func IsSuccessful(failureCodes ...codes.Code) func(error) bool {
+	set := make(map[codes.Code]struct{}, len(failureCodes))
+	for _, c := range failureCodes {
+		set[c] = struct{}{}
+	}
+
+	return func(err error) bool {
+		if err == nil {
+			return true
+		}
+		_, isFailure := set[status.Code(err)]
+		return !isFailure
+	}
+}
+
+// DefaultIsSuccessful treats Unavailable and DeadlineExceeded as breaker
+// failures, matching gobreaker/Mimir's defaults.
+var DefaultIsSuccessful = IsSuccessful(codes.Unavailable, codes.DeadlineExceeded)
+
+// Options configures the interceptors in this package.
+type Options struct {
+	// IsSuccessful classifies a call's error for the breaker. Defaults to
+	// DefaultIsSuccessful.
+	IsSuccessful func(error) bool
+}
+
+func (o Options) isSuccessful() func(error) bool {
+	if o.IsSuccessful != nil {
+		return o.IsSuccessful
+	}
+	return DefaultIsSuccessful
+}
+
+// UnaryClientInterceptor runs unary calls through cb, classifying the
+// result via opts.IsSuccessful. The call's error is always returned to the
+// caller unchanged; only the classification reaches cb's accounting.
+func UnaryClientInterceptor(cb *circuitbreaker.CircuitBreaker, opts Options) grpc.UnaryClientInterceptor {
+	isSuccessful := opts.isSuccessful()
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		var callErr error
+		called := false
+
+		_, err := circuitbreaker.Execute(cb, func() (struct{}, error) {
+			called = true
+			callErr = invoker(ctx, method, req, reply, cc, callOpts...)
+			if isSuccessful(callErr) {
+				return struct{}{}, nil
+			}
+			return struct{}{}, callErr
+		})
+
+		if !called {
+			// cb rejected the call outright (OPEN, or too many HALFOPEN probes).
+			return err
+		}
+
+		return callErr
+	}
+}
+
+// StreamClientInterceptor runs stream creation through cb, classifying the
+// result via opts.IsSuccessful. Errors that surface later on the stream
+// itself are not observed by cb.
+func StreamClientInterceptor(cb *circuitbreaker.CircuitBreaker, opts Options) grpc.StreamClientInterceptor {
+	isSuccessful := opts.isSuccessful()
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var stream grpc.ClientStream
+		var streamErr error
+		called := false
+
+		_, err := circuitbreaker.Execute(cb, func() (struct{}, error) {
+			called = true
+			stream, streamErr = streamer(ctx, desc, cc, method, callOpts...)
+			if isSuccessful(streamErr) {
+				return struct{}{}, nil
+			}
+			return struct{}{}, streamErr
+		})
+
+		if !called {
+			return nil, err
+		}
+
+		return stream, streamErr
+	}
+}
@@ -0,0 +1,89 @@
+package grpcbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+	"github.com/ansakharov/educational_circuit_breaker/grpcbreaker"
+)
+
+func newTestBreaker(t *testing.T) *circuitbreaker.CircuitBreaker {
+	t.Helper()
+
+	return circuitbreaker.NewCircuitBreaker(circuitbreaker.Settings{
+		Timeout:     time.Second,
+		Percentile:  0.5,
+		MinRequests: 1,
+	})
+}
+
+func TestUnaryClientInterceptorReturnsRealCallError(t *testing.T) {
+	cb := newTestBreaker(t)
+	interceptor := grpcbreaker.UnaryClientInterceptor(cb, grpcbreaker.Options{})
+
+	wantErr := status.Error(codes.NotFound, "no such thing")
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return wantErr
+	}
+
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	// NotFound isn't in DefaultIsSuccessful's failure set, so it must not
+	// count as a breaker failure.
+	counts := cb.Counts()
+	if counts.TotalFailures != 0 {
+		t.Fatalf("counts = %+v, want 0 failures for a NotFound error", counts)
+	}
+}
+
+func TestUnaryClientInterceptorClassifiesConfiguredFailureCodes(t *testing.T) {
+	cb := newTestBreaker(t)
+	interceptor := grpcbreaker.UnaryClientInterceptor(cb, grpcbreaker.Options{
+		IsSuccessful: grpcbreaker.DefaultIsSuccessful,
+	})
+
+	wantErr := status.Error(codes.Unavailable, "down")
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return wantErr
+	}
+
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+
+	counts := cb.Counts()
+	if counts.TotalFailures != 1 {
+		t.Fatalf("counts = %+v, want exactly 1 failure for Unavailable", counts)
+	}
+}
+
+func TestUnaryClientInterceptorRejectsWhenBreakerOpen(t *testing.T) {
+	cb := newTestBreaker(t)
+	interceptor := grpcbreaker.UnaryClientInterceptor(cb, grpcbreaker.Options{})
+
+	failing := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "down")
+	}
+
+	if err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failing); err == nil {
+		t.Fatal("tripping call: want an error")
+	}
+	// The breaker only detects the trip on the precheck of the next call.
+	if err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failing); err == nil {
+		t.Fatal("expected a rejection once the breaker trips, got nil")
+	}
+	if state := cb.State(); state != circuitbreaker.OPEN {
+		t.Fatalf("state = %v, want OPEN", state)
+	}
+}
@@ -0,0 +1,86 @@
+// Package httpbreaker adapts circuitbreaker.CircuitBreaker to the
+// net/http.RoundTripper interface, so any http.Client can be protected by a
+// circuit breaker with one line of wiring.
+package httpbreaker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+)
+
+// IsSuccessful classifies the outcome of a single round trip. resp is nil
+// whenever err is non-nil.
+type IsSuccessful func(resp *http.Response, err error) bool
+
+// Options configures NewTransport.
+type Options struct {
+	// Next is the underlying RoundTripper. Defaults to http.DefaultTransport.
+	Next http.RoundTripper
+	// IsSuccessful classifies the response. Defaults to treating network
+	// errors and 5xx responses as failures; 2xx/3xx/4xx count as success.
+	IsSuccessful IsSuccessful
+}
+
+type transport struct {
+	cb           *circuitbreaker.CircuitBreaker
+	next         http.RoundTripper
+	isSuccessful IsSuccessful
+}
+
+// NewTransport returns an http.RoundTripper that runs every request through
+// cb, classifying the response via opts.IsSuccessful.
+func NewTransport(cb *circuitbreaker.CircuitBreaker, opts Options) http.RoundTripper {
+	t := &transport{
+		cb:           cb,
+		next:         opts.Next,
+		isSuccessful: opts.IsSuccessful,
+	}
+
+	if t.next == nil {
+		t.next = http.DefaultTransport
+	}
+	if t.isSuccessful == nil {
+		t.isSuccessful = defaultIsSuccessful
+	}
+
+	return t
+}
+
+func defaultIsSuccessful(resp *http.Response, err error) bool {
+	if err != nil {
+		return false
+	}
+	return resp.StatusCode < 500
+}
+
+// RoundTrip implements http.RoundTripper. A response the breaker classifies
+// as unsuccessful (e.g. a 5xx) is still a valid response under
+// http.RoundTripper's contract, so it's returned to the caller unchanged
+// alongside a nil error; only cb's own internal accounting sees the
+// synthetic error driving isSuccessful's verdict.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var roundTripErr error
+	called := false
+
+	_, breakerErr := circuitbreaker.Execute(t.cb, func() (struct{}, error) {
+		called = true
+		resp, roundTripErr = t.next.RoundTrip(req)
+		if t.isSuccessful(resp, roundTripErr) {
+			return struct{}{}, nil
+		}
+		if roundTripErr != nil {
+			return struct{}{}, roundTripErr
+		}
+		return struct{}{}, fmt.Errorf("httpbreaker: unsuccessful response: %s", resp.Status)
+	})
+
+	if !called {
+		// cb rejected the call outright (OPEN, or too many HALFOPEN probes).
+		return nil, breakerErr
+	}
+
+	return resp, roundTripErr
+}
@@ -0,0 +1,52 @@
+package httpbreaker_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ansakharov/educational_circuit_breaker/circuitbreaker"
+	"github.com/ansakharov/educational_circuit_breaker/httpbreaker"
+)
+
+func TestRoundTripReturnsResponseOnUnsuccessfulStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer srv.Close()
+
+	cb := circuitbreaker.NewCircuitBreaker(circuitbreaker.Settings{
+		Timeout:        time.Second,
+		Percentile:     0.5,
+		MinRequests:    1,
+		BucketCount:    5,
+		BucketDuration: time.Hour,
+	})
+	client := &http.Client{Transport: httpbreaker.NewTransport(cb, httpbreaker.Options{})}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("got err %v, want nil (a 5xx is still a valid response)", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if string(body) != "boom" {
+		t.Fatalf("body = %q, want %q", body, "boom")
+	}
+
+	counts := cb.Counts()
+	if counts.TotalFailures != 1 {
+		t.Fatalf("counts = %+v, want exactly 1 failure recorded for the 5xx", counts)
+	}
+}